@@ -7,18 +7,18 @@ import (
 	"testing"
 	"time"
 
+	"github.com/chainmint/core/generator/simulated"
 	"github.com/chainmint/crypto/ed25519/chainkd"
 	"github.com/chainmint/database/pg/pgtest"
 	"github.com/chainmint/errors"
 	"github.com/chainmint/protocol/bc"
-	"github.com/chainmint/protocol/prottest"
 	"github.com/chainmint/protocol/vm"
 	"github.com/chainmint/testutil"
 )
 
 func TestCreateAccount(t *testing.T) {
 	db := pgtest.NewTx(t)
-	m := NewManager(db, prottest.NewChain(t), nil)
+	m := NewManager(db, simulated.NewChain(t), nil)
 	ctx := context.Background()
 
 	account, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "", nil, "")
@@ -40,7 +40,7 @@ func TestCreateAccount(t *testing.T) {
 
 func TestCreateAccountIdempotency(t *testing.T) {
 	db := pgtest.NewTx(t)
-	m := NewManager(db, prottest.NewChain(t), nil)
+	m := NewManager(db, simulated.NewChain(t), nil)
 	ctx := context.Background()
 	var clientToken = "a-unique-client-token"
 
@@ -59,7 +59,7 @@ func TestCreateAccountIdempotency(t *testing.T) {
 
 func TestCreateAccountReusedAlias(t *testing.T) {
 	db := pgtest.NewTx(t)
-	m := NewManager(db, prottest.NewChain(t), nil)
+	m := NewManager(db, simulated.NewChain(t), nil)
 	ctx := context.Background()
 	m.createTestAccount(ctx, t, "some-account", nil)
 
@@ -72,7 +72,7 @@ func TestCreateAccountReusedAlias(t *testing.T) {
 func TestCreateControlProgram(t *testing.T) {
 	// use pgtest.NewDB for deterministic postgres sequences
 	_, db := pgtest.NewDB(t, pgtest.SchemaPath)
-	m := NewManager(db, prottest.NewChain(t), nil)
+	m := NewManager(db, simulated.NewChain(t), nil)
 	ctx := context.Background()
 
 	account, err := m.Create(ctx, []chainkd.XPub{testutil.TestXPub}, 1, "", nil, "")
@@ -151,7 +151,7 @@ func (m *Manager) createTestUTXO(ctx context.Context, t testing.TB, accountID st
 
 func TestFindByID(t *testing.T) {
 	db := pgtest.NewTx(t)
-	m := NewManager(db, prottest.NewChain(t), nil)
+	m := NewManager(db, simulated.NewChain(t), nil)
 	ctx := context.Background()
 	account := m.createTestAccount(ctx, t, "", nil)
 
@@ -167,7 +167,7 @@ func TestFindByID(t *testing.T) {
 
 func TestFindByAlias(t *testing.T) {
 	db := pgtest.NewTx(t)
-	m := NewManager(db, prottest.NewChain(t), nil)
+	m := NewManager(db, simulated.NewChain(t), nil)
 	ctx := context.Background()
 	account := m.createTestAccount(ctx, t, "some-alias", nil)
 
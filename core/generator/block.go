@@ -60,6 +60,10 @@ func (g *Generator) MakeBlock(ctx context.Context, time uint64) (error, []byte)
 		if err != nil {
 			log.Fatalkv(ctx, log.KeyError, err)
 		}
+		// b's vote, if any, was already tallied and its ConsensusProgram
+		// already rewritten before it was saved as pending. Only resync
+		// g.signers to match; don't tally the vote a second time.
+		g.resyncSigners(b)
 	} else {
 		g.mu.Lock()
 		txs := g.pool
@@ -71,9 +75,18 @@ func (g *Generator) MakeBlock(ctx context.Context, time uint64) (error, []byte)
 		if err != nil {
 			return errors.Wrap(err, "generate"), nil
 		}
-		if len(b.Transactions) == 0 {
+		// Take the pending vote before the empty-block check below: a
+		// queued vote must still be attached and tallied even when there
+		// are no transactions to include, or signer rotation could stall
+		// forever on a chain with no steady transaction traffic.
+		b.Vote = g.takePendingVote()
+		if len(b.Transactions) == 0 && b.Vote == nil {
 			return nil, b.Hash().Bytes() // don't bother making an empty block
 		}
+		err = g.applySignerVotes(ctx, b, latestBlock)
+		if err != nil {
+			return errors.Wrap(err, "applying signer vote"), nil
+		}
 		err = savePendingBlock(ctx, g.db, b)
 		if err != nil {
 			return errors.Wrap(err, "saving pending block"), nil
@@ -92,6 +105,11 @@ func (g *Generator) commitBlock(ctx context.Context, b *legacy.Block, s *state.S
 	if err != nil {
 		return errors.Wrap(err, "commit"), nil
 	}
+
+	if g.txIndexer != nil {
+		g.txIndexer.IndexBlock(ctx, b)
+	}
+
 	return nil, b.Hash().Bytes()
 }
 
@@ -100,6 +118,13 @@ func (g *Generator) getAndAddBlockSignatures(ctx context.Context, b, prevBlock *
 		return nil // no signatures needed for initial block
 	}
 
+	g.lifecycleMu.Lock()
+	signCtx := g.ctx
+	g.lifecycleMu.Unlock()
+	if signCtx == nil {
+		return errNotStarted
+	}
+
 	pubkeys, quorum, err := vmutil.ParseBlockMultiSigProgram(prevBlock.ConsensusProgram)
 	if err != nil {
 		return errors.Wrap(err, "parsing prevblock output script")
@@ -114,14 +139,19 @@ func (g *Generator) getAndAddBlockSignatures(ctx context.Context, b, prevBlock *
 		return errors.Wrap(err, "marshalling block")
 	}
 
-	ctx, cancel := context.WithCancel(ctx)
+	// signCtx is derived from the generator's own lifecycle context, not
+	// the caller's, so that Stop can bound every in-flight signing
+	// goroutine even after this call returns and its local cancel fires.
+	signCtx, cancel := context.WithCancel(signCtx)
 	defer cancel()
+	deadline := time.Now().Add(g.signDeadline)
 
 	goodSigs := make([][]byte, len(pubkeys))
 	replies := make([][]byte, len(g.signers))
 	done := make(chan int, len(g.signers))
 	for i, signer := range g.signers {
-		go getSig(ctx, signer, marshalledBlock, &replies[i], i, done)
+		g.wg.Add(1)
+		go g.getSig(signCtx, signer, marshalledBlock, deadline, &replies[i], i, done)
 	}
 
 	nready := 0
@@ -155,9 +185,14 @@ func indexKey(keys []ed25519.PublicKey, msg, sig []byte) int {
 	return -1
 }
 
-func getSig(ctx context.Context, signer BlockSigner, marshalledBlock []byte, sig *[]byte, i int, done chan int) {
+// getSig requests a signature from signer, passing it deadline, and
+// reports itself done on the wait group Stop waits on once signer's
+// SignBlockWithDeadline call returns. A signer that honors neither ctx
+// nor deadline keeps this goroutine, and Stop, waiting.
+func (g *Generator) getSig(ctx context.Context, signer BlockSigner, marshalledBlock []byte, deadline time.Time, sig *[]byte, i int, done chan int) {
+	defer g.wg.Done()
 	var err error
-	*sig, err = signer.SignBlock(ctx, marshalledBlock)
+	*sig, err = signer.SignBlockWithDeadline(ctx, marshalledBlock, deadline)
 	if err != nil && ctx.Err() != context.Canceled {
 		log.Printkv(ctx, "error", err, "signer", signer)
 	}
@@ -0,0 +1,102 @@
+// Package generator implements the block generator, the component
+// responsible for assembling, signing, and committing new blocks on
+// behalf of the signers that make up the block-signer quorum.
+package generator
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/chainmint/core/generator/txindex"
+	"github.com/chainmint/crypto/ed25519"
+	"github.com/chainmint/database/pg"
+	"github.com/chainmint/protocol"
+	"github.com/chainmint/protocol/bc"
+	"github.com/chainmint/protocol/bc/legacy"
+)
+
+// BlockSigner defines the interface for a signer that the generator
+// requests signatures from during block generation. PublicKey
+// identifies the signer within a block's multisig ConsensusProgram and
+// in governance votes cast for or against it. SignBlockWithDeadline
+// gives RPC-backed signers a hard budget to respond within, so a
+// wedged signer can't hold a signing goroutine open indefinitely.
+type BlockSigner interface {
+	SignBlock(context.Context, []byte) ([]byte, error)
+	SignBlockWithDeadline(ctx context.Context, block []byte, deadline time.Time) ([]byte, error)
+	PublicKey() ed25519.PublicKey
+}
+
+// Generator collects pending transactions, assembles them into a
+// block, gathers the required signatures from the configured signers,
+// and commits the resulting block to the blockchain.
+type Generator struct {
+	chain   *protocol.Chain
+	signers []BlockSigner
+	db      pg.DB
+
+	mu         sync.Mutex
+	pool       []*legacy.Tx
+	poolHashes map[bc.Hash]bool
+
+	// ownKey identifies the ed25519 public key this generator process
+	// signs governance votes under, if it proposes any. It is nil for
+	// generators that never cast votes of their own.
+	ownKey ed25519.PublicKey
+
+	// signerEpoch is the number of blocks that make up one governance
+	// epoch. Pending vote tallies are cleared at each epoch boundary.
+	signerEpoch uint64
+
+	voteMu      sync.Mutex
+	pendingVote *legacy.BlockVote
+
+	// txIndexer, if set, is notified of every block the generator
+	// commits so it can maintain the tx_hash lookup table in the
+	// background.
+	txIndexer *txindex.Indexer
+
+	// lifecycleMu guards ctx and cancel, which Start establishes and
+	// Stop tears down.
+	lifecycleMu sync.Mutex
+	ctx         context.Context
+	cancel      context.CancelFunc
+
+	// wg tracks every in-flight signing goroutine, so Stop can wait for
+	// them to finish instead of abandoning them.
+	wg sync.WaitGroup
+
+	// signDeadline bounds how long the generator waits for any one
+	// signer's SignBlockWithDeadline call.
+	signDeadline time.Duration
+}
+
+// New returns a new Generator that produces blocks for chain, using db
+// for persistence, and requests signatures from signers. Call Start
+// before MakeBlock.
+func New(chain *protocol.Chain, signers []BlockSigner, db pg.DB) *Generator {
+	return &Generator{
+		chain:        chain,
+		signers:      signers,
+		db:           db,
+		poolHashes:   make(map[bc.Hash]bool),
+		signerEpoch:  defaultSignerEpoch,
+		signDeadline: defaultSignDeadline,
+	}
+}
+
+// SetOwnKey identifies the signer key this generator process casts its
+// own governance votes under, via ProposeVote. It has no effect on
+// votes carried by recovered or externally-produced blocks.
+func (g *Generator) SetOwnKey(key ed25519.PublicKey) {
+	g.ownKey = key
+}
+
+// SetTxIndexer installs idx to be notified of every block this
+// generator commits. Start owns starting idx: it launches idx's
+// backfill and worker goroutine itself, so SetTxIndexer must be called
+// before Start, not followed by a separate idx.Start call.
+func (g *Generator) SetTxIndexer(idx *txindex.Indexer) {
+	g.txIndexer = idx
+}
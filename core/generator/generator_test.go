@@ -0,0 +1,116 @@
+package generator
+
+import (
+	"context"
+	"crypto/rand"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/chainmint/crypto/ed25519"
+	"github.com/chainmint/database/pg/pgtest"
+	"github.com/chainmint/errors"
+	"github.com/chainmint/protocol/bc/legacy"
+	"github.com/chainmint/protocol/prottest"
+	"github.com/chainmint/protocol/vmutil"
+	"github.com/chainmint/testutil"
+)
+
+// stuckSigner simulates an RPC signer whose SignBlock call hangs
+// forever; only SignBlockWithDeadline's hard budget brings it back.
+type stuckSigner struct {
+	pub ed25519.PublicKey
+}
+
+func (s stuckSigner) PublicKey() ed25519.PublicKey { return s.pub }
+
+func (s stuckSigner) SignBlock(ctx context.Context, block []byte) ([]byte, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (s stuckSigner) SignBlockWithDeadline(ctx context.Context, block []byte, deadline time.Time) ([]byte, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(time.Until(deadline)):
+		return nil, errors.New("stuck signer timed out")
+	}
+}
+
+type okSigner struct {
+	pub  ed25519.PublicKey
+	priv ed25519.PrivateKey
+}
+
+func (s okSigner) PublicKey() ed25519.PublicKey { return s.pub }
+
+func (s okSigner) SignBlock(ctx context.Context, block []byte) ([]byte, error) {
+	return s.sign(block)
+}
+
+func (s okSigner) SignBlockWithDeadline(ctx context.Context, block []byte, deadline time.Time) ([]byte, error) {
+	return s.sign(block)
+}
+
+// sign signs the hash of the marshalled block, which is what
+// getAndAddBlockSignatures verifies signatures against.
+func (s okSigner) sign(block []byte) ([]byte, error) {
+	var b legacy.Block
+	err := b.UnmarshalText(block)
+	if err != nil {
+		return nil, err
+	}
+	return ed25519.Sign(s.priv, b.Hash().Bytes()), nil
+}
+
+// TestStopDoesNotLeakGoroutinesOnStuckSigner reproduces the leak this
+// commit fixes: before SignBlockWithDeadline, a wedged signer's
+// goroutine could only end when its underlying RPC finally timed out,
+// which repeated MakeBlock cycles would accumulate. With a bounded
+// deadline and Stop's WaitGroup, goroutine count should return to
+// baseline after Stop.
+func TestStopDoesNotLeakGoroutinesOnStuckSigner(t *testing.T) {
+	db := pgtest.NewTx(t)
+	chain := prottest.NewChain(t)
+
+	stuckPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	okPub, okPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	program, err := vmutil.BlockMultiSigProgram([]ed25519.PublicKey{stuckPub, okPub}, 1)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	g := New(chain, []BlockSigner{stuckSigner{stuckPub}, okSigner{okPub, okPriv}}, db)
+	g.SetSignDeadline(20 * time.Millisecond)
+
+	ctx := context.Background()
+	g.Start(ctx)
+
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 5; i++ {
+		b := &legacy.Block{Height: uint64(i + 2)}
+		prev := &legacy.Block{ConsensusProgram: program}
+		err := g.getAndAddBlockSignatures(ctx, b, prev)
+		if err != nil {
+			t.Fatalf("iteration %d: %v", i, err)
+		}
+	}
+
+	g.Stop()
+
+	// Give the runtime a moment to reclaim the stopped goroutines'
+	// stacks before sampling.
+	time.Sleep(50 * time.Millisecond)
+	if after := runtime.NumGoroutine(); after > before+1 {
+		t.Errorf("got %d goroutines after Stop, want at most %d (started with %d)", after, before+1, before)
+	}
+}
@@ -0,0 +1,68 @@
+package generator
+
+import (
+	"context"
+	"time"
+
+	"github.com/chainmint/errors"
+	"github.com/chainmint/log"
+)
+
+// defaultSignDeadline bounds how long the generator waits for any one
+// signer's SignBlockWithDeadline call before giving up on it. It can be
+// overridden with SetSignDeadline.
+const defaultSignDeadline = 5 * time.Second
+
+// errNotStarted is returned by MakeBlock when it is called before
+// Start, since signing goroutines need the context Start establishes
+// in order to be bounded by Stop.
+var errNotStarted = errors.New("generator not started")
+
+// Start prepares the generator to produce blocks: it derives the
+// context used to bound every in-flight signing goroutine from ctx,
+// and, if a tx indexer is installed, starts it. Start must be called
+// before MakeBlock.
+func (g *Generator) Start(ctx context.Context) {
+	g.lifecycleMu.Lock()
+	defer g.lifecycleMu.Unlock()
+	g.ctx, g.cancel = context.WithCancel(ctx)
+	if g.txIndexer != nil {
+		g.txIndexer.Start(g.ctx)
+	}
+}
+
+// Stop cancels the context Start established, waits for every
+// in-flight signing goroutine to finish, and logs any pending-block row
+// still left uncommitted. The row itself is left in place, for the next
+// MakeBlock to recover. Stop blocks until all signing goroutines have
+// returned, which SetSignDeadline bounds.
+//
+// Stop does not close g.db: db is a constructor argument the generator
+// never owns, since the same pg.DB handle is typically shared with
+// every other Core subsystem. A caller that opened its own db for the
+// generator (see generator/simulated) is responsible for closing it
+// after Stop returns.
+func (g *Generator) Stop() {
+	g.lifecycleMu.Lock()
+	cancel := g.cancel
+	g.lifecycleMu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+
+	g.wg.Wait()
+
+	ctx := context.Background()
+	pending, err := getPendingBlock(ctx, g.db)
+	if err != nil {
+		log.Printkv(ctx, "error", err, "message", "checking pending block during shutdown")
+	} else if pending != nil {
+		log.Printkv(ctx, "message", "generator stopped with an uncommitted pending block", "height", pending.Height)
+	}
+}
+
+// SetSignDeadline overrides how long the generator waits for any one
+// signer's SignBlockWithDeadline call before giving up on it.
+func (g *Generator) SetSignDeadline(d time.Duration) {
+	g.signDeadline = d
+}
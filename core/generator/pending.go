@@ -0,0 +1,70 @@
+package generator
+
+import (
+	"context"
+
+	"github.com/chainmint/errors"
+	"github.com/chainmint/protocol/bc"
+	"github.com/chainmint/protocol/bc/legacy"
+	"github.com/chainmint/protocol/state"
+)
+
+// Pending generates, but does not sign, save, or commit, a preview of
+// the block the generator would produce from its current transaction
+// pool at timestamp t. It is a read-only peek used by test harnesses
+// (see generator/simulated) that want to inspect pending transactions
+// and state without advancing the chain.
+func (g *Generator) Pending(ctx context.Context, t uint64) (*legacy.Block, *state.Snapshot, error) {
+	latestBlock, latestSnapshot := g.chain.State()
+
+	g.mu.Lock()
+	txs := append([]*legacy.Tx{}, g.pool...)
+	g.mu.Unlock()
+
+	b, s, err := g.chain.GenerateBlock(ctx, latestBlock, latestSnapshot, t, txs)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "generating pending block preview")
+	}
+	return b, s, nil
+}
+
+// DiscardPendingBlock discards any block saved by a previous MakeBlock
+// call that has not yet been committed, and restores its transactions
+// to the pool ahead of any submitted since, so a subsequent MakeBlock
+// can retry with the same or additional transactions. It is used by
+// test harnesses that need to roll back a generated block instead of
+// committing it.
+func (g *Generator) DiscardPendingBlock(ctx context.Context) error {
+	pending, err := getPendingBlock(ctx, g.db)
+	if err != nil {
+		return errors.Wrap(err, "retrieving pending block to discard")
+	}
+
+	const q = `DELETE FROM generator_pending_block`
+	_, err = g.db.Exec(ctx, q)
+	if err != nil {
+		return errors.Wrap(err, "discarding pending block")
+	}
+	if pending == nil {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	hashes := make(map[bc.Hash]bool, len(pending.Transactions)+len(g.pool))
+	txs := make([]*legacy.Tx, 0, len(pending.Transactions)+len(g.pool))
+	for _, tx := range pending.Transactions {
+		hashes[tx.Hash] = true
+		txs = append(txs, tx)
+	}
+	for _, tx := range g.pool {
+		if hashes[tx.Hash] {
+			continue
+		}
+		hashes[tx.Hash] = true
+		txs = append(txs, tx)
+	}
+	g.pool = txs
+	g.poolHashes = hashes
+	return nil
+}
@@ -0,0 +1,217 @@
+package simulated
+
+import (
+	gosql "database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/chainmint/database/pg"
+)
+
+// memDB is a tiny in-memory stand-in for Postgres, backing the
+// generator's own generator_pending_block and generator_signer_votes
+// tables. It exists so generator/simulated can drive a real Generator
+// without a Postgres connection. It understands only the handful of
+// queries the generator package itself issues; it is not a general
+// SQL engine.
+var driverSeq int32
+
+// newMemDB opens a fresh, isolated in-memory database and returns it
+// as a pg.DB.
+func newMemDB() (pg.DB, error) {
+	name := fmt.Sprintf("chainmint-memdb-%d", atomic.AddInt32(&driverSeq, 1))
+	gosql.Register(name, &memDriver{store: newMemStore()})
+	db, err := gosql.Open(name, "")
+	if err != nil {
+		return nil, err
+	}
+	return pg.WrapDB(db), nil
+}
+
+type memStore struct {
+	mu sync.Mutex
+
+	pendingData   []byte
+	pendingHeight int64
+	havePending   bool
+
+	votes map[voteKey]bool
+}
+
+type voteKey struct {
+	epoch     int64
+	op        int64
+	candidate string
+	voter     string
+}
+
+func newMemStore() *memStore {
+	return &memStore{votes: make(map[voteKey]bool)}
+}
+
+type memDriver struct {
+	store *memStore
+}
+
+func (d *memDriver) Open(name string) (driver.Conn, error) {
+	return &memConn{store: d.store}, nil
+}
+
+type memConn struct {
+	store *memStore
+}
+
+func (c *memConn) Prepare(query string) (driver.Stmt, error) {
+	return &memStmt{conn: c, query: query}, nil
+}
+
+func (c *memConn) Close() error              { return nil }
+func (c *memConn) Begin() (driver.Tx, error) { return memTx{}, nil }
+
+type memTx struct{}
+
+func (memTx) Commit() error   { return nil }
+func (memTx) Rollback() error { return nil }
+
+type memStmt struct {
+	conn  *memConn
+	query string
+}
+
+func (s *memStmt) Close() error  { return nil }
+func (s *memStmt) NumInput() int { return -1 }
+
+func (s *memStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.exec(s.query, args)
+}
+
+func (s *memStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.query(s.query, args)
+}
+
+func (c *memConn) exec(query string, args []driver.Value) (driver.Result, error) {
+	q := normalize(query)
+	store := c.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	switch {
+	case strings.Contains(q, "insert into generator_pending_block"):
+		store.pendingData = args[0].([]byte)
+		store.pendingHeight = toInt64(args[1])
+		store.havePending = true
+		return driver.RowsAffected(1), nil
+
+	case strings.Contains(q, "insert into generator_signer_votes"):
+		key := voteKey{
+			epoch:     toInt64(args[0]),
+			op:        toInt64(args[1]),
+			candidate: string(args[2].([]byte)),
+			voter:     string(args[3].([]byte)),
+		}
+		// A voter gets one vote per candidate per epoch, whatever its op.
+		for k := range store.votes {
+			if k.epoch == key.epoch && k.candidate == key.candidate && k.voter == key.voter {
+				return driver.RowsAffected(0), nil
+			}
+		}
+		store.votes[key] = true
+		return driver.RowsAffected(1), nil
+
+	case strings.Contains(q, "delete from generator_signer_votes where epoch = "):
+		epoch := toInt64(args[0])
+		var n int64
+		for k := range store.votes {
+			if k.epoch == epoch {
+				delete(store.votes, k)
+				n++
+			}
+		}
+		return driver.RowsAffected(n), nil
+
+	case strings.Contains(q, "delete from generator_signer_votes where epoch < "):
+		epoch := toInt64(args[0])
+		var n int64
+		for k := range store.votes {
+			if k.epoch < epoch {
+				delete(store.votes, k)
+				n++
+			}
+		}
+		return driver.RowsAffected(n), nil
+
+	case strings.Contains(q, "delete from generator_pending_block"):
+		var n int64
+		if store.havePending {
+			n = 1
+		}
+		store.havePending = false
+		store.pendingData = nil
+		return driver.RowsAffected(n), nil
+	}
+
+	return nil, fmt.Errorf("simulated memdb: unsupported statement: %s", query)
+}
+
+func (c *memConn) query(query string, args []driver.Value) (driver.Rows, error) {
+	q := normalize(query)
+	store := c.store
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	switch {
+	case strings.Contains(q, "select data from generator_pending_block"):
+		if !store.havePending {
+			return &memRows{cols: []string{"data"}}, nil
+		}
+		return &memRows{cols: []string{"data"}, rows: [][]driver.Value{{store.pendingData}}}, nil
+
+	case strings.Contains(q, "select count(*) from generator_signer_votes"):
+		epoch, op, candidate := toInt64(args[0]), toInt64(args[1]), string(args[2].([]byte))
+		var n int64
+		for k := range store.votes {
+			if k.epoch == epoch && k.op == op && k.candidate == candidate {
+				n++
+			}
+		}
+		return &memRows{cols: []string{"count"}, rows: [][]driver.Value{{n}}}, nil
+	}
+
+	return nil, fmt.Errorf("simulated memdb: unsupported query: %s", query)
+}
+
+type memRows struct {
+	cols []string
+	rows [][]driver.Value
+	i    int
+}
+
+func (r *memRows) Columns() []string { return r.cols }
+func (r *memRows) Close() error      { return nil }
+
+func (r *memRows) Next(dest []driver.Value) error {
+	if r.i >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.i])
+	r.i++
+	return nil
+}
+
+func normalize(q string) string {
+	return strings.ToLower(strings.TrimSpace(q))
+}
+
+func toInt64(v driver.Value) int64 {
+	switch n := v.(type) {
+	case int64:
+		return n
+	case int:
+		return int64(n)
+	}
+	return 0
+}
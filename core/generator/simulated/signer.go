@@ -0,0 +1,55 @@
+package simulated
+
+import (
+	"context"
+	"crypto/rand"
+	"time"
+
+	"github.com/chainmint/crypto/ed25519"
+	"github.com/chainmint/errors"
+	"github.com/chainmint/protocol/bc/legacy"
+)
+
+// localSigner implements generator.BlockSigner using an ed25519 key
+// held in memory, standing in for the networked RPC signers a real
+// deployment talks to.
+type localSigner struct {
+	pub  ed25519.PublicKey
+	priv ed25519.PrivateKey
+}
+
+func newLocalSigner() (*localSigner, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "generating simulated signer key")
+	}
+	return &localSigner{pub: pub, priv: priv}, nil
+}
+
+func (s *localSigner) PublicKey() ed25519.PublicKey {
+	return s.pub
+}
+
+func (s *localSigner) SignBlock(ctx context.Context, marshalledBlock []byte) ([]byte, error) {
+	return s.sign(marshalledBlock)
+}
+
+// SignBlockWithDeadline signs immediately; deadline is unused since a
+// local key never blocks on a network round trip.
+func (s *localSigner) SignBlockWithDeadline(ctx context.Context, marshalledBlock []byte, deadline time.Time) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.sign(marshalledBlock)
+}
+
+// sign signs the hash of the marshalled block, which is what the
+// generator verifies block signatures against.
+func (s *localSigner) sign(marshalledBlock []byte) ([]byte, error) {
+	var b legacy.Block
+	err := b.UnmarshalText(marshalledBlock)
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshalling block to sign")
+	}
+	return ed25519.Sign(s.priv, b.Hash().Bytes()), nil
+}
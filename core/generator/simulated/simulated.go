@@ -0,0 +1,175 @@
+// Package simulated provides an in-memory Generator backend for unit
+// tests and SDK integration tests that want to drive real block
+// production without Postgres or networked signers, in the style of
+// go-ethereum's simulated backend.
+package simulated
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/chainmint/core/generator"
+	"github.com/chainmint/crypto/ed25519"
+	"github.com/chainmint/database/pg"
+	"github.com/chainmint/errors"
+	"github.com/chainmint/log"
+	"github.com/chainmint/protocol"
+	"github.com/chainmint/protocol/bc/legacy"
+	"github.com/chainmint/protocol/state"
+	"github.com/chainmint/protocol/vmutil"
+)
+
+// GenesisAlloc seeds the simulated chain's genesis state with UTXOs
+// controlled by the given control programs, keyed by their hex
+// encoding, analogous to go-ethereum's GenesisAlloc.
+type GenesisAlloc map[string]uint64
+
+// Backend wraps a Generator, an in-memory protocol.Chain, and a mock
+// clock, giving tests explicit control over when blocks are produced.
+type Backend struct {
+	Generator *generator.Generator
+
+	chain    *protocol.Chain
+	db       pg.DB
+	signer   *localSigner
+	gasLimit uint64
+
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewSimulatedBackend seeds a genesis block, installs a locally held
+// ed25519 key as the sole block signer, and returns a Backend ready to
+// accept submitted transactions and produce blocks on demand. gasLimit
+// is recorded and reported by GasLimit; it does not limit the
+// transactions included in a block.
+func NewSimulatedBackend(ctx context.Context, alloc GenesisAlloc, gasLimit uint64) (*Backend, error) {
+	signer, err := newLocalSigner()
+	if err != nil {
+		return nil, err
+	}
+
+	program, err := vmutil.BlockMultiSigProgram([]ed25519.PublicKey{signer.PublicKey()}, 1)
+	if err != nil {
+		return nil, errors.Wrap(err, "building genesis consensus program")
+	}
+	initialBlock, err := legacy.NewInitialBlock(program, alloc)
+	if err != nil {
+		return nil, errors.Wrap(err, "building genesis block")
+	}
+
+	db, err := newMemDB()
+	if err != nil {
+		return nil, errors.Wrap(err, "opening simulated memdb")
+	}
+
+	chain, err := protocol.NewChain(ctx, db, nil, initialBlock)
+	if err != nil {
+		return nil, errors.Wrap(err, "initializing simulated chain")
+	}
+
+	g := generator.New(chain, []generator.BlockSigner{signer}, db)
+	g.SetOwnKey(signer.PublicKey())
+	g.Start(ctx)
+
+	return &Backend{
+		Generator: g,
+		chain:     chain,
+		db:        db,
+		signer:    signer,
+		gasLimit:  gasLimit,
+		now:       time.Unix(0, 0),
+	}, nil
+}
+
+// Chain returns the backend's underlying chain, for callers (such as
+// the account package's tests) that want to drive the chain directly
+// alongside the generator, in place of prottest.NewChain.
+func (b *Backend) Chain() *protocol.Chain {
+	return b.chain
+}
+
+// DB returns the memdb-backed pg.DB the backend opened for its
+// generator.
+func (b *Backend) DB() pg.DB {
+	return b.db
+}
+
+// GasLimit returns the gasLimit the backend was constructed with.
+func (b *Backend) GasLimit() uint64 {
+	return b.gasLimit
+}
+
+// Close stops the backend's generator, waiting for any in-flight
+// signing goroutines, and closes the memdb the backend opened for it.
+// The backend, not the generator, owns this handle, since the same
+// Generator.Stop is also used in production against a shared Postgres
+// pool it must not close.
+func (b *Backend) Close() {
+	b.Generator.Stop()
+	if closer, ok := b.db.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.Printkv(context.Background(), "error", err, "message", "closing simulated memdb handle")
+		}
+	}
+}
+
+// Commit forces the generator to assemble, sign, and commit whatever
+// transactions are pending, sealing the block with timestamp t, and
+// returns the sealed block. It also advances the backend's mock clock
+// to t, so that a subsequent AdjustTime call is relative to this
+// block's timestamp rather than wall-clock time. If no transactions or
+// votes are pending, no new block is produced and Commit returns the
+// chain's latest block.
+func (b *Backend) Commit(ctx context.Context, t time.Time) (*legacy.Block, error) {
+	b.mu.Lock()
+	b.now = t
+	b.mu.Unlock()
+
+	err, _ := b.Generator.MakeBlock(ctx, uint64(t.Unix()))
+	if err != nil {
+		return nil, err
+	}
+	latest, _ := b.chain.State()
+	return latest, nil
+}
+
+// Rollback discards the block most recently produced by MakeBlock that
+// has not yet been committed, returning its transactions to the pool
+// so a subsequent Commit can retry with the same or additional
+// transactions.
+func (b *Backend) Rollback(ctx context.Context) error {
+	return b.Generator.DiscardPendingBlock(ctx)
+}
+
+// AdjustTime advances the backend's mock clock by d. It affects the
+// timestamp used by subsequent PendingBlock and PendingState calls;
+// Commit uses the timestamp passed to it instead.
+func (b *Backend) AdjustTime(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.now = b.now.Add(d)
+}
+
+// PendingBlock returns a preview of the block that would be produced
+// by Commit, without signing or committing it.
+func (b *Backend) PendingBlock(ctx context.Context) (*legacy.Block, error) {
+	block, _, err := b.pending(ctx)
+	return block, err
+}
+
+// PendingState returns the snapshot of chain state that would result
+// from committing PendingBlock.
+func (b *Backend) PendingState(ctx context.Context) (*state.Snapshot, error) {
+	_, snapshot, err := b.pending(ctx)
+	return snapshot, err
+}
+
+func (b *Backend) pending(ctx context.Context) (*legacy.Block, *state.Snapshot, error) {
+	b.mu.Lock()
+	now := b.now
+	b.mu.Unlock()
+	return b.Generator.Pending(ctx, uint64(now.Unix()))
+}
@@ -0,0 +1,131 @@
+package simulated
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/chainmint/core/generator"
+	"github.com/chainmint/crypto/ed25519"
+	"github.com/chainmint/protocol"
+	"github.com/chainmint/protocol/bc"
+	"github.com/chainmint/protocol/bc/legacy"
+	"github.com/chainmint/protocol/vmutil"
+)
+
+func TestCommitEmptyBlock(t *testing.T) {
+	ctx := context.Background()
+	backend, err := NewSimulatedBackend(ctx, nil, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	block, err := backend.Commit(ctx, time.Unix(1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block == nil {
+		t.Fatal("Commit returned a nil block")
+	}
+}
+
+func TestRollbackClearsPendingBlock(t *testing.T) {
+	ctx := context.Background()
+	backend, err := NewSimulatedBackend(ctx, nil, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	err = backend.Rollback(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error rolling back with no pending block: %v", err)
+	}
+}
+
+// TestRollbackRestoresSubmittedTransactions exercises the data-loss bug
+// Rollback used to have: it builds a generator whose consensus program
+// requires two signatures but is only given one working signer, so
+// MakeBlock saves a pending block and then fails to reach quorum
+// signing it, leaving a real pending-but-uncommitted block on disk.
+// Rollback must recover the block's transactions into the pool rather
+// than discarding them.
+func TestRollbackRestoresSubmittedTransactions(t *testing.T) {
+	ctx := context.Background()
+
+	signerA, err := newLocalSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signerB, err := newLocalSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := vmutil.BlockMultiSigProgram([]ed25519.PublicKey{signerA.PublicKey(), signerB.PublicKey()}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	initialBlock, err := legacy.NewInitialBlock(program, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := newMemDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain, err := protocol.NewChain(ctx, db, nil, initialBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only signerA is handed to the generator, so the two-signature
+	// quorum the consensus program requires can never be reached.
+	g := generator.New(chain, []generator.BlockSigner{signerA}, db)
+	g.Start(ctx)
+	defer g.Stop()
+
+	var txHash bc.Hash
+	_, err = txHash.ReadFrom(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := &legacy.Tx{Hash: txHash}
+	err = g.Submit(ctx, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err, _ = g.MakeBlock(ctx, uint64(time.Now().Unix()))
+	if err == nil {
+		t.Fatal("expected MakeBlock to fail to reach the two-signature quorum")
+	}
+
+	err = g.DiscardPendingBlock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pending, _, err := g.Pending(ctx, uint64(time.Now().Unix()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending.Transactions) != 1 || pending.Transactions[0].Hash != tx.Hash {
+		t.Fatalf("got %d pending transactions after rollback, want the 1 submitted transaction restored to the pool", len(pending.Transactions))
+	}
+}
+
+func TestAdjustTime(t *testing.T) {
+	ctx := context.Background()
+	backend, err := NewSimulatedBackend(ctx, nil, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	backend.AdjustTime(5 * time.Second)
+	if got := backend.now; got.Unix() != 5 {
+		t.Errorf("got mock clock = %v, want 5s after epoch", got)
+	}
+}
@@ -0,0 +1,24 @@
+package simulated
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/chainmint/protocol"
+)
+
+// NewChain returns a *protocol.Chain backed by a fresh simulated
+// Backend, for tests that want to drive real block production without
+// Postgres or networked signers. It mirrors prottest.NewChain's
+// single-argument, fatal-on-error convention, so existing callers of
+// prottest.NewChain(t) can switch to simulated.NewChain(t) in place.
+// The backend is stopped automatically when t completes.
+func NewChain(t testing.TB) *protocol.Chain {
+	backend, err := NewSimulatedBackend(context.Background(), nil, math.MaxInt64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(backend.Close)
+	return backend.Chain()
+}
@@ -0,0 +1,21 @@
+package generator
+
+import (
+	"context"
+
+	"github.com/chainmint/protocol/bc/legacy"
+)
+
+// Submit adds tx to the generator's transaction pool, to be included
+// in the next block produced by MakeBlock. Submitting the same
+// transaction twice is a no-op.
+func (g *Generator) Submit(ctx context.Context, tx *legacy.Tx) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.poolHashes[tx.Hash] {
+		return nil
+	}
+	g.poolHashes[tx.Hash] = true
+	g.pool = append(g.pool, tx)
+	return nil
+}
@@ -0,0 +1,77 @@
+package txindex
+
+import (
+	"context"
+	"time"
+
+	"github.com/chainmint/errors"
+	"github.com/chainmint/log"
+)
+
+// progressInterval is how often backfill logs its progress.
+const progressInterval = 5 * time.Second
+
+// backfill indexes every block from the persisted cursor up to the
+// chain's current height, a one-shot catch-up run over history that
+// predates the indexer (or that it missed while not running). It logs
+// its rate, blocks remaining, and estimated time to completion every
+// progressInterval.
+func (idx *Indexer) backfill(ctx context.Context) error {
+	cursor, err := getCursor(ctx, idx.db)
+	if err != nil {
+		return errors.Wrap(err, "reading backfill cursor")
+	}
+
+	target := idx.chain.Height()
+	if cursor >= target {
+		return nil
+	}
+
+	log.Printkv(ctx, "message", "starting txindex backfill", "from_height", cursor+1, "to_height", target)
+
+	start := time.Now()
+	lastLog := start
+	indexed := 0
+	for height := cursor + 1; height <= target; height++ {
+		b, err := idx.chain.GetBlock(ctx, height)
+		if err != nil {
+			return errors.Wrapf(err, "fetching block %d for backfill", height)
+		}
+		err = idx.indexBlock(ctx, b)
+		if err != nil {
+			return errors.Wrapf(err, "indexing block %d during backfill", height)
+		}
+		indexed++
+
+		if now := time.Now(); now.Sub(lastLog) >= progressInterval {
+			logBackfillProgress(ctx, start, now, indexed, target-height)
+			lastLog = now
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+	}
+
+	log.Printkv(ctx, "message", "txindex backfill complete", "blocks_indexed", indexed)
+	return nil
+}
+
+func logBackfillProgress(ctx context.Context, start, now time.Time, indexed int, remaining uint64) {
+	elapsed := now.Sub(start)
+	if elapsed <= 0 || indexed == 0 {
+		return
+	}
+	rate := float64(indexed) / elapsed.Seconds() // blocks per second
+	var eta time.Duration
+	if rate > 0 {
+		eta = time.Duration(float64(remaining)/rate) * time.Second
+	}
+	log.Printkv(ctx, "message", "txindex backfill progress",
+		"blocks_indexed", indexed,
+		"blocks_remaining", remaining,
+		"rate_per_sec", rate,
+		"eta", eta)
+}
@@ -0,0 +1,182 @@
+// Package txindex maintains a tx_hash -> (block_hash, block_height,
+// tx_index) lookup table, kept up to date as the generator commits new
+// blocks, so higher layers can translate a raw transaction hash into
+// the block that included it without scanning the whole chain.
+package txindex
+
+import (
+	"context"
+
+	"github.com/chainmint/database/pg"
+	"github.com/chainmint/database/sql"
+	"github.com/chainmint/errors"
+	"github.com/chainmint/log"
+	"github.com/chainmint/protocol/bc"
+	"github.com/chainmint/protocol/bc/legacy"
+)
+
+// blockQueueSize bounds the number of committed blocks that may be
+// waiting for the indexing worker at once. Block production blocks on
+// enqueuing only if the indexer has fallen this far behind.
+const blockQueueSize = 256
+
+// Location identifies where a transaction was included in the
+// blockchain.
+type Location struct {
+	BlockHash   bc.Hash
+	BlockHeight uint64
+	TxIndex     int
+}
+
+// Indexer asynchronously maintains the transaction lookup table. Writes
+// for committed blocks happen on a single background goroutine started
+// by Start, so that block production is never blocked on index writes.
+// Unindex writes on the caller's goroutine.
+type Indexer struct {
+	db    pg.DB
+	chain chain
+
+	blocks chan *legacy.Block
+	done   chan struct{}
+}
+
+// chain is the subset of *protocol.Chain the indexer needs in order to
+// backfill blocks that were committed before it started.
+type chain interface {
+	GetBlock(context.Context, uint64) (*legacy.Block, error)
+	Height() uint64
+}
+
+// NewIndexer returns an Indexer that records lookups in db for blocks
+// committed to chain.
+func NewIndexer(db pg.DB, c chain) *Indexer {
+	return &Indexer{
+		db:     db,
+		chain:  c,
+		blocks: make(chan *legacy.Block, blockQueueSize),
+		done:   make(chan struct{}),
+	}
+}
+
+// Start launches the indexing worker goroutine, backfilling any blocks
+// committed after the cursor the indexer last persisted, then
+// indexing newly enqueued blocks as they arrive via IndexBlock. Start
+// returns once the worker goroutine has been launched; it does not
+// wait for the backfill to finish.
+func (idx *Indexer) Start(ctx context.Context) {
+	go idx.run(ctx)
+}
+
+// IndexBlock enqueues b to be indexed by the background worker. It
+// blocks only if the worker has fallen blockQueueSize blocks behind.
+func (idx *Indexer) IndexBlock(ctx context.Context, b *legacy.Block) {
+	select {
+	case idx.blocks <- b:
+	case <-ctx.Done():
+	}
+}
+
+func (idx *Indexer) run(ctx context.Context) {
+	err := idx.backfill(ctx)
+	if err != nil {
+		log.Printkv(ctx, "error", err, "message", "txindex backfill failed")
+	}
+
+	for {
+		select {
+		case b := <-idx.blocks:
+			err := idx.indexBlock(ctx, b)
+			if err != nil {
+				log.Printkv(ctx, "error", err, "block_height", b.Height, "message", "indexing committed block")
+			}
+		case <-ctx.Done():
+			close(idx.done)
+			return
+		}
+	}
+}
+
+// indexBlock records the lookup entries for every transaction in b and
+// advances the persisted cursor to b.Height.
+func (idx *Indexer) indexBlock(ctx context.Context, b *legacy.Block) error {
+	for i, tx := range b.Transactions {
+		err := idx.insertLookup(ctx, tx.Hash, b.Hash(), b.Height, i)
+		if err != nil {
+			return errors.Wrap(err, "indexing transaction")
+		}
+	}
+	return setCursor(ctx, idx.db, b.Height)
+}
+
+func (idx *Indexer) insertLookup(ctx context.Context, txHash, blockHash bc.Hash, height uint64, txIndex int) error {
+	const q = `
+		INSERT INTO tx_index (tx_hash, block_hash, block_height, tx_index)
+		VALUES($1, $2, $3, $4)
+		ON CONFLICT (tx_hash) DO UPDATE
+			SET block_hash = excluded.block_hash,
+				block_height = excluded.block_height,
+				tx_index = excluded.tx_index
+	`
+	_, err := idx.db.Exec(ctx, q, txHash, blockHash, height, txIndex)
+	return err
+}
+
+// Lookup translates txHash into the block that included it, without
+// scanning the blockchain. It returns sql.ErrNoRows if txHash has not
+// been indexed.
+func (idx *Indexer) Lookup(ctx context.Context, txHash bc.Hash) (*Location, error) {
+	const q = `
+		SELECT block_hash, block_height, tx_index FROM tx_index WHERE tx_hash = $1
+	`
+	var loc Location
+	err := idx.db.QueryRow(ctx, q, txHash).Scan(&loc.BlockHash, &loc.BlockHeight, &loc.TxIndex)
+	if err == sql.ErrNoRows {
+		return nil, err
+	} else if err != nil {
+		return nil, errors.Wrap(err, "looking up indexed transaction")
+	}
+	return &loc, nil
+}
+
+// Unindex removes lookup entries for every block at height fromHeight
+// and above, for rolling back indexed blocks that are not part of the
+// canonical chain, and resets the cursor to fromHeight-1 so Start will
+// backfill them again if they are superseded by different blocks at
+// the same heights.
+func (idx *Indexer) Unindex(ctx context.Context, fromHeight uint64) error {
+	const q = `DELETE FROM tx_index WHERE block_height >= $1`
+	_, err := idx.db.Exec(ctx, q, fromHeight)
+	if err != nil {
+		return errors.Wrap(err, "unindexing transactions")
+	}
+
+	var cursor uint64
+	if fromHeight > 0 {
+		cursor = fromHeight - 1
+	}
+	return setCursor(ctx, idx.db, cursor)
+}
+
+func setCursor(ctx context.Context, db pg.DB, height uint64) error {
+	const q = `
+		INSERT INTO tx_index_cursor (singleton, height) VALUES(true, $1)
+		ON CONFLICT (singleton) DO UPDATE SET height = excluded.height
+	`
+	_, err := db.Exec(ctx, q, height)
+	if err != nil {
+		return errors.Wrap(err, "tx_index_cursor upsert query")
+	}
+	return nil
+}
+
+func getCursor(ctx context.Context, db pg.DB) (uint64, error) {
+	const q = `SELECT height FROM tx_index_cursor`
+	var height uint64
+	err := db.QueryRow(ctx, q).Scan(&height)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
+		return 0, errors.Wrap(err, "reading tx_index_cursor")
+	}
+	return height, nil
+}
@@ -0,0 +1,106 @@
+package txindex
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+
+	"github.com/chainmint/database/pg/pgtest"
+	"github.com/chainmint/database/sql"
+	"github.com/chainmint/protocol/bc"
+	"github.com/chainmint/protocol/bc/legacy"
+	"github.com/chainmint/testutil"
+)
+
+type fakeChain struct {
+	blocks []*legacy.Block
+}
+
+func (c *fakeChain) Height() uint64 {
+	return uint64(len(c.blocks))
+}
+
+func (c *fakeChain) GetBlock(ctx context.Context, height uint64) (*legacy.Block, error) {
+	if height == 0 || height > uint64(len(c.blocks)) {
+		return nil, sql.ErrNoRows
+	}
+	return c.blocks[height-1], nil
+}
+
+func randHash(t *testing.T) (h bc.Hash) {
+	_, err := h.ReadFrom(rand.Reader)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	return h
+}
+
+func testBlock(t *testing.T, height uint64) (*legacy.Block, bc.Hash) {
+	txHash := randHash(t)
+	b := &legacy.Block{
+		Height:       height,
+		Transactions: []*legacy.Tx{{Hash: txHash}},
+	}
+	return b, txHash
+}
+
+func TestIndexAndLookup(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	idx := NewIndexer(db, &fakeChain{})
+
+	b, txHash := testBlock(t, 1)
+	err := idx.indexBlock(ctx, b)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	loc, err := idx.Lookup(ctx, txHash)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if loc.BlockHeight != 1 {
+		t.Errorf("got block height %d, want 1", loc.BlockHeight)
+	}
+}
+
+func TestLookupMiss(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	idx := NewIndexer(db, &fakeChain{})
+
+	_, err := idx.Lookup(ctx, randHash(t))
+	if err != sql.ErrNoRows {
+		t.Errorf("got error %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestUnindexResetsCursor(t *testing.T) {
+	ctx := context.Background()
+	db := pgtest.NewTx(t)
+	idx := NewIndexer(db, &fakeChain{})
+
+	b, txHash := testBlock(t, 1)
+	err := idx.indexBlock(ctx, b)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	err = idx.Unindex(ctx, 1)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	_, err = idx.Lookup(ctx, txHash)
+	if err != sql.ErrNoRows {
+		t.Errorf("got error %v after unindex, want sql.ErrNoRows", err)
+	}
+
+	cursor, err := getCursor(ctx, db)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if cursor != 0 {
+		t.Errorf("got cursor %d after unindexing height 1, want 0", cursor)
+	}
+}
@@ -0,0 +1,252 @@
+package generator
+
+import (
+	"context"
+
+	"github.com/chainmint/crypto/ed25519"
+	"github.com/chainmint/database/pg"
+	"github.com/chainmint/errors"
+	"github.com/chainmint/log"
+	"github.com/chainmint/protocol/bc/legacy"
+	"github.com/chainmint/protocol/vmutil"
+)
+
+// defaultSignerEpoch is used when a Generator is constructed without an
+// explicit epoch length. It can be overridden with SetSignerEpoch.
+const defaultSignerEpoch = 10000
+
+var (
+	// errUnauthorizedVoter is returned when a block carries a vote cast
+	// by a key outside the currently authorized signer set.
+	errUnauthorizedVoter = errors.New("vote cast by unauthorized signer")
+
+	// errDuplicateVote is returned when the same signer has already
+	// voted on the same candidate key within the current epoch.
+	errDuplicateVote = errors.New("duplicate vote for candidate in current epoch")
+)
+
+// minSigners is the smallest authorized signer set applySignerVotes will
+// ever rotate to. A removal vote that would drop the set below this
+// floor is ignored rather than applied, since an empty signer set could
+// never sign, or vote to recover, another block.
+const minSigners = 1
+
+// SetSignerEpoch overrides the number of blocks that make up one
+// governance epoch. It must be called before the generator starts
+// producing blocks.
+func (g *Generator) SetSignerEpoch(n uint64) {
+	g.signerEpoch = n
+}
+
+// ProposeVote arranges for the next block this generator produces to
+// carry a governance vote endorsing the addition or removal of
+// candidate from the signer set. The vote is attached to exactly one
+// block; callers that want a vote to persist across epochs must call
+// ProposeVote again after it is consumed.
+func (g *Generator) ProposeVote(op legacy.VoteOp, candidate ed25519.PublicKey) {
+	g.voteMu.Lock()
+	defer g.voteMu.Unlock()
+	g.pendingVote = &legacy.BlockVote{
+		Op:        op,
+		Candidate: candidate,
+		Voter:     g.ownKey,
+	}
+}
+
+// takePendingVote returns and clears the vote queued by ProposeVote, if
+// any.
+func (g *Generator) takePendingVote() *legacy.BlockVote {
+	g.voteMu.Lock()
+	defer g.voteMu.Unlock()
+	v := g.pendingVote
+	g.pendingVote = nil
+	return v
+}
+
+// applySignerVotes records the vote carried by b, if any, against the
+// signer set authorized by prevBlock's consensus program. It rejects a
+// vote cast by a non-authorized signer or a duplicate vote from the
+// same signer on the same candidate within the current epoch. Once a
+// candidate has the support of strictly more than half of the
+// currently authorized signers, it rewrites b.ConsensusProgram to
+// reflect the new signer set and drops from g.signers any signer
+// outside that set. An added key has no BlockSigner here, so it is not
+// added to g.signers. A removal vote that would shrink the signer set
+// below minSigners is ignored instead of applied.
+func (g *Generator) applySignerVotes(ctx context.Context, b, prevBlock *legacy.Block) error {
+	if b.Vote == nil || prevBlock == nil {
+		return nil
+	}
+
+	pubkeys, quorum, err := vmutil.ParseBlockMultiSigProgram(prevBlock.ConsensusProgram)
+	if err != nil {
+		return errors.Wrap(err, "parsing prevblock output script")
+	}
+	if !isAuthorizedSigner(pubkeys, b.Vote.Voter) {
+		return errUnauthorizedVoter
+	}
+
+	epoch := b.Height / g.signerEpoch
+	if err := sweepStaleEpochs(ctx, g.db, epoch); err != nil {
+		return errors.Wrap(err, "sweeping stale signer vote tallies")
+	}
+
+	tally, err := recordSignerVote(ctx, g.db, epoch, b.Vote)
+	if err != nil {
+		return err
+	}
+	if tally*2 <= len(pubkeys) {
+		return nil // not yet a strict majority of authorized signers
+	}
+
+	newPubkeys, newQuorum := rotatePubkeys(pubkeys, quorum, b.Vote)
+	if len(newPubkeys) < minSigners {
+		log.Printkv(ctx, "message", "ignoring signer removal vote that would leave too few authorized signers",
+			"candidate", b.Vote.Candidate, "min_signers", minSigners)
+		return nil
+	}
+
+	program, err := vmutil.BlockMultiSigProgram(newPubkeys, newQuorum)
+	if err != nil {
+		return errors.Wrap(err, "building rotated consensus program")
+	}
+	b.ConsensusProgram = program
+
+	g.mu.Lock()
+	g.signers = filterSigners(g.signers, newPubkeys)
+	g.mu.Unlock()
+
+	err = clearSignerVotes(ctx, g.db, epoch)
+	if err != nil {
+		return errors.Wrap(err, "clearing signer vote tallies")
+	}
+
+	log.Printkv(ctx, "message", "rotated block-signer set", "op", b.Vote.Op, "candidate", b.Vote.Candidate, "quorum", newQuorum)
+	return nil
+}
+
+// resyncSigners restores g.signers to match the signer set authorized
+// by a recovered pending block's consensus program. It is called when
+// the generator recovers a pending block across a leader failover,
+// since that block's vote, if any, was already tallied and applied
+// before it was saved and must not be tallied again.
+func (g *Generator) resyncSigners(pending *legacy.Block) {
+	if pending == nil {
+		return
+	}
+	pubkeys, _, err := vmutil.ParseBlockMultiSigProgram(pending.ConsensusProgram)
+	if err != nil {
+		return
+	}
+	g.mu.Lock()
+	g.signers = filterSigners(g.signers, pubkeys)
+	g.mu.Unlock()
+}
+
+// isAuthorizedSigner reports whether voter is among pubkeys.
+func isAuthorizedSigner(pubkeys []ed25519.PublicKey, voter ed25519.PublicKey) bool {
+	for _, k := range pubkeys {
+		if k.Equal(voter) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordSignerVote persists a single signer's vote for a candidate key
+// within epoch, returning the number of distinct authorized signers
+// that have voted the same way on that candidate so far this epoch. It
+// returns errDuplicateVote if voter has already voted on candidate
+// during epoch.
+func recordSignerVote(ctx context.Context, db pg.DB, epoch uint64, vote *legacy.BlockVote) (int, error) {
+	const insertQ = `
+		INSERT INTO generator_signer_votes (epoch, op, candidate, voter)
+		VALUES($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING
+	`
+	res, err := db.Exec(ctx, insertQ, epoch, vote.Op, []byte(vote.Candidate), []byte(vote.Voter))
+	if err != nil {
+		return 0, errors.Wrap(err, "generator_signer_votes insert query")
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(err, "generator_signer_votes rows affected")
+	}
+	if affected == 0 {
+		return 0, errDuplicateVote
+	}
+
+	const countQ = `
+		SELECT COUNT(*) FROM generator_signer_votes
+		WHERE epoch = $1 AND op = $2 AND candidate = $3
+	`
+	var tally int
+	err = db.QueryRow(ctx, countQ, epoch, vote.Op, []byte(vote.Candidate)).Scan(&tally)
+	if err != nil {
+		return 0, errors.Wrap(err, "counting signer votes")
+	}
+	return tally, nil
+}
+
+// clearSignerVotes discards all tallies recorded for epoch, since a
+// rotation has just consumed them.
+func clearSignerVotes(ctx context.Context, db pg.DB, epoch uint64) error {
+	const q = `DELETE FROM generator_signer_votes WHERE epoch = $1`
+	_, err := db.Exec(ctx, q, epoch)
+	return err
+}
+
+// sweepStaleEpochs discards any vote tallies left over from an epoch
+// older than epoch. An epoch's tallies are normally cleared by
+// clearSignerVotes once a vote reaches majority, but an epoch can also
+// roll over without ever reaching majority (or with a majority rejected
+// by the minSigners floor), which would otherwise leave its rows in
+// generator_signer_votes forever. Sweeping on every vote keeps the
+// table from growing unbounded over the life of a long-running chain.
+func sweepStaleEpochs(ctx context.Context, db pg.DB, epoch uint64) error {
+	const q = `DELETE FROM generator_signer_votes WHERE epoch < $1`
+	_, err := db.Exec(ctx, q, epoch)
+	return err
+}
+
+// rotatePubkeys applies vote to pubkeys, returning the new authorized
+// key set and the quorum it should require. Adding a signer preserves
+// the existing quorum; removing one shrinks the quorum by one, down to
+// a minimum of 1.
+func rotatePubkeys(pubkeys []ed25519.PublicKey, quorum int, vote *legacy.BlockVote) ([]ed25519.PublicKey, int) {
+	switch vote.Op {
+	case legacy.VoteAddSigner:
+		if isAuthorizedSigner(pubkeys, vote.Candidate) {
+			return pubkeys, quorum
+		}
+		return append(append([]ed25519.PublicKey{}, pubkeys...), vote.Candidate), quorum
+	case legacy.VoteRemoveSigner:
+		if !isAuthorizedSigner(pubkeys, vote.Candidate) {
+			return pubkeys, quorum
+		}
+		kept := make([]ed25519.PublicKey, 0, len(pubkeys))
+		for _, k := range pubkeys {
+			if k.Equal(vote.Candidate) {
+				continue
+			}
+			kept = append(kept, k)
+		}
+		if newQuorum := quorum - 1; newQuorum >= 1 {
+			quorum = newQuorum
+		}
+		return kept, quorum
+	}
+	return pubkeys, quorum
+}
+
+// filterSigners returns the subset of signers whose public key appears
+// in pubkeys, preserving order.
+func filterSigners(signers []BlockSigner, pubkeys []ed25519.PublicKey) []BlockSigner {
+	kept := make([]BlockSigner, 0, len(signers))
+	for _, s := range signers {
+		if isAuthorizedSigner(pubkeys, s.PublicKey()) {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,174 @@
+package generator
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+
+	"github.com/chainmint/crypto/ed25519"
+	"github.com/chainmint/database/pg/pgtest"
+	"github.com/chainmint/errors"
+	"github.com/chainmint/protocol/bc/legacy"
+	"github.com/chainmint/protocol/vmutil"
+	"github.com/chainmint/testutil"
+)
+
+func genTestKey(t *testing.T) ed25519.PublicKey {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	return pub
+}
+
+func testProgram(t *testing.T, quorum int, pubkeys ...ed25519.PublicKey) []byte {
+	program, err := vmutil.BlockMultiSigProgram(pubkeys, quorum)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	return program
+}
+
+func TestApplySignerVotesMajorityRotatesSigners(t *testing.T) {
+	db := pgtest.NewTx(t)
+	a, b2, c := genTestKey(t), genTestKey(t), genTestKey(t)
+	candidate := genTestKey(t)
+
+	g := New(nil, nil, db)
+	prev := &legacy.Block{ConsensusProgram: testProgram(t, 2, a, b2, c)}
+	ctx := context.Background()
+
+	first := &legacy.Block{Vote: &legacy.BlockVote{Op: legacy.VoteAddSigner, Candidate: candidate, Voter: a}}
+	err := g.applySignerVotes(ctx, first, prev)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if first.ConsensusProgram != nil {
+		t.Fatal("expected no rotation after a single vote short of a majority")
+	}
+
+	second := &legacy.Block{Vote: &legacy.BlockVote{Op: legacy.VoteAddSigner, Candidate: candidate, Voter: b2}}
+	err = g.applySignerVotes(ctx, second, prev)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if second.ConsensusProgram == nil {
+		t.Fatal("expected a majority vote to rotate the consensus program")
+	}
+	gotPubkeys, gotQuorum, err := vmutil.ParseBlockMultiSigProgram(second.ConsensusProgram)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if gotQuorum != 2 {
+		t.Errorf("got quorum %d, want 2", gotQuorum)
+	}
+	if len(gotPubkeys) != 4 {
+		t.Errorf("got %d signers, want 4", len(gotPubkeys))
+	}
+}
+
+func TestApplySignerVotesRejectsUnauthorizedVoter(t *testing.T) {
+	db := pgtest.NewTx(t)
+	a, b2 := genTestKey(t), genTestKey(t)
+	outsider := genTestKey(t)
+	candidate := genTestKey(t)
+
+	g := New(nil, nil, db)
+	prev := &legacy.Block{ConsensusProgram: testProgram(t, 1, a, b2)}
+	blk := &legacy.Block{Vote: &legacy.BlockVote{Op: legacy.VoteAddSigner, Candidate: candidate, Voter: outsider}}
+
+	err := g.applySignerVotes(context.Background(), blk, prev)
+	if errors.Root(err) != errUnauthorizedVoter {
+		t.Errorf("got error %v, want errUnauthorizedVoter", err)
+	}
+}
+
+func TestApplySignerVotesRejectsDuplicateVote(t *testing.T) {
+	db := pgtest.NewTx(t)
+	a, b2 := genTestKey(t), genTestKey(t)
+	candidate := genTestKey(t)
+
+	g := New(nil, nil, db)
+	prev := &legacy.Block{ConsensusProgram: testProgram(t, 1, a, b2)}
+	vote := &legacy.BlockVote{Op: legacy.VoteAddSigner, Candidate: candidate, Voter: a}
+
+	err := g.applySignerVotes(context.Background(), &legacy.Block{Vote: vote}, prev)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	err = g.applySignerVotes(context.Background(), &legacy.Block{Vote: vote}, prev)
+	if errors.Root(err) != errDuplicateVote {
+		t.Errorf("got error %v, want errDuplicateVote", err)
+	}
+}
+
+func TestApplySignerVotesWontRemoveLastSigner(t *testing.T) {
+	db := pgtest.NewTx(t)
+	solo := genTestKey(t)
+
+	g := New(nil, nil, db)
+	prev := &legacy.Block{ConsensusProgram: testProgram(t, 1, solo)}
+	blk := &legacy.Block{Vote: &legacy.BlockVote{Op: legacy.VoteRemoveSigner, Candidate: solo, Voter: solo}}
+
+	err := g.applySignerVotes(context.Background(), blk, prev)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if blk.ConsensusProgram != nil {
+		t.Fatal("expected removing the sole authorized signer to be ignored, not applied")
+	}
+}
+
+func TestApplySignerVotesEpochRolloverClearsTally(t *testing.T) {
+	db := pgtest.NewTx(t)
+	a, b2, c := genTestKey(t), genTestKey(t), genTestKey(t)
+	candidate := genTestKey(t)
+
+	g := New(nil, nil, db)
+	g.SetSignerEpoch(1)
+	prev := &legacy.Block{ConsensusProgram: testProgram(t, 2, a, b2, c)}
+	ctx := context.Background()
+
+	vote := &legacy.BlockVote{Op: legacy.VoteAddSigner, Candidate: candidate, Voter: a}
+	err := g.applySignerVotes(ctx, &legacy.Block{Height: 1, Vote: vote}, prev)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	// Height 2 falls in a new epoch (signerEpoch == 1), so a's vote here
+	// shouldn't be counted toward the tally recorded at height 1.
+	blk := &legacy.Block{Height: 2, Vote: &legacy.BlockVote{Op: legacy.VoteAddSigner, Candidate: candidate, Voter: a}}
+	err = g.applySignerVotes(ctx, blk, prev)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+	if blk.ConsensusProgram != nil {
+		t.Fatal("expected a single vote in a new epoch to not already be a majority")
+	}
+}
+
+func TestSweepStaleEpochsRemovesOldTallies(t *testing.T) {
+	db := pgtest.NewTx(t)
+	ctx := context.Background()
+	voter := genTestKey(t)
+	candidate := genTestKey(t)
+	vote := &legacy.BlockVote{Op: legacy.VoteAddSigner, Candidate: candidate, Voter: voter}
+
+	_, err := recordSignerVote(ctx, db, 1, vote)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	err = sweepStaleEpochs(ctx, db, 2)
+	if err != nil {
+		testutil.FatalErr(t, err)
+	}
+
+	// Recording the same vote again in epoch 1 should succeed now that
+	// sweepStaleEpochs cleared its abandoned tally, instead of failing
+	// with errDuplicateVote.
+	_, err = recordSignerVote(ctx, db, 1, vote)
+	if err != nil {
+		t.Errorf("got error %v recording a vote after its epoch was swept, want nil", err)
+	}
+}